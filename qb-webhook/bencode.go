@@ -0,0 +1,126 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+)
+
+// bDecoder is a minimal bencode decoder covering just what's needed to read a .torrent
+// file's metainfo dict: integers, byte strings, lists and dictionaries. Pulling in a full
+// BitTorrent client library (with its DHT/uTP/crypto transitive dependencies) just to parse
+// a bencode dict is overkill for this.
+type bDecoder struct {
+	data []byte
+	pos  int
+}
+
+func newBDecoder(data []byte) *bDecoder {
+	return &bDecoder{data: data}
+}
+
+// decode reads one bencoded value at the current position and returns it as int64, string,
+// []interface{} or map[string]interface{}.
+func (d *bDecoder) decode() (interface{}, error) {
+	if d.pos >= len(d.data) {
+		return nil, fmt.Errorf("unexpected end of input at offset %d", d.pos)
+	}
+
+	switch c := d.data[d.pos]; {
+	case c == 'i':
+		return d.decodeInt()
+	case c == 'l':
+		return d.decodeList()
+	case c == 'd':
+		return d.decodeDict()
+	case c >= '0' && c <= '9':
+		return d.decodeString()
+	default:
+		return nil, fmt.Errorf("invalid bencode token %q at offset %d", c, d.pos)
+	}
+}
+
+func (d *bDecoder) decodeInt() (int64, error) {
+	if d.pos >= len(d.data) || d.data[d.pos] != 'i' {
+		return 0, fmt.Errorf("expected 'i' at offset %d", d.pos)
+	}
+	end := d.indexFrom(d.pos+1, 'e')
+	if end < 0 {
+		return 0, fmt.Errorf("unterminated integer starting at offset %d", d.pos)
+	}
+	n, err := strconv.ParseInt(string(d.data[d.pos+1:end]), 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid integer at offset %d: %v", d.pos, err)
+	}
+	d.pos = end + 1
+	return n, nil
+}
+
+func (d *bDecoder) decodeString() (string, error) {
+	colon := d.indexFrom(d.pos, ':')
+	if colon < 0 {
+		return "", fmt.Errorf("malformed string length at offset %d", d.pos)
+	}
+	length, err := strconv.Atoi(string(d.data[d.pos:colon]))
+	if err != nil || length < 0 {
+		return "", fmt.Errorf("invalid string length at offset %d: %v", d.pos, err)
+	}
+	start := colon + 1
+	end := start + length
+	if end > len(d.data) {
+		return "", fmt.Errorf("string at offset %d overruns input", d.pos)
+	}
+	d.pos = end
+	return string(d.data[start:end]), nil
+}
+
+func (d *bDecoder) decodeList() ([]interface{}, error) {
+	d.pos++ // consume 'l'
+	var list []interface{}
+	for {
+		if d.pos >= len(d.data) {
+			return nil, fmt.Errorf("unterminated list")
+		}
+		if d.data[d.pos] == 'e' {
+			d.pos++
+			return list, nil
+		}
+		v, err := d.decode()
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, v)
+	}
+}
+
+func (d *bDecoder) decodeDict() (map[string]interface{}, error) {
+	d.pos++ // consume 'd'
+	dict := make(map[string]interface{})
+	for {
+		if d.pos >= len(d.data) {
+			return nil, fmt.Errorf("unterminated dictionary")
+		}
+		if d.data[d.pos] == 'e' {
+			d.pos++
+			return dict, nil
+		}
+		key, err := d.decodeString()
+		if err != nil {
+			return nil, fmt.Errorf("decode dict key: %v", err)
+		}
+		v, err := d.decode()
+		if err != nil {
+			return nil, fmt.Errorf("decode value for key %q: %v", key, err)
+		}
+		dict[key] = v
+	}
+}
+
+// indexFrom returns the offset of the first occurrence of b at or after pos, or -1.
+func (d *bDecoder) indexFrom(pos int, b byte) int {
+	for i := pos; i < len(d.data); i++ {
+		if d.data[i] == b {
+			return i
+		}
+	}
+	return -1
+}
@@ -0,0 +1,202 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// BatchPayload wraps a batch of coalesced events into a single webhook delivery.
+type BatchPayload struct {
+	Events []Payload `json:"events"`
+}
+
+// socketPath resolves cfg.SocketPath, defaulting to "qb-webhook.sock" next to the executable.
+func socketPath(cfg Config) (string, error) {
+	if cfg.SocketPath != "" {
+		return cfg.SocketPath, nil
+	}
+	ex, err := os.Executable()
+	if err != nil {
+		return "", fmt.Errorf("resolve executable path: %v", err)
+	}
+	return filepath.Join(filepath.Dir(ex), "qb-webhook.sock"), nil
+}
+
+// forwardToDaemon checks whether a --daemon sidecar is listening on cfg's socket and, if so,
+// forwards payload to it for batching. The bool return is false when no daemon is reachable
+// or the write to it fails, and callers should then fall back to sendWebhook; it is true once
+// the payload was written to the daemon, or when marshaling fails (reported in the error).
+func forwardToDaemon(cfg Config, payload Payload) (bool, error) {
+	path, err := socketPath(cfg)
+	if err != nil {
+		return false, nil
+	}
+	if _, err := os.Stat(path); err != nil {
+		return false, nil
+	}
+
+	conn, err := net.DialTimeout("unix", path, 2*time.Second)
+	if err != nil {
+		// The socket file exists but nothing is listening (stale socket); fall back.
+		return false, nil
+	}
+	defer conn.Close()
+
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return true, fmt.Errorf("marshal payload: %v", err)
+	}
+	data = append(data, '\n')
+
+	if _, err := conn.Write(data); err != nil {
+		// We dialed successfully but the daemon died (or the socket was otherwise severed)
+		// before it could read the payload; we know for certain it never arrived, so fall
+		// back to a direct POST instead of dropping it.
+		log.Printf("Warning: write to daemon socket failed, falling back to direct delivery: %v", err)
+		return false, nil
+	}
+	return true, nil
+}
+
+// spoolBatch hands a batch that failed delivery off to the same queue_dir/--drain-queue path
+// that sendWebhook uses, spooling each event individually so a receiver outage during a burst
+// doesn't silently drop every event the daemon had coalesced.
+func spoolBatch(cfg Config, events []Payload) {
+	for _, payload := range events {
+		if err := spoolPayload(cfg, payload, 1); err != nil {
+			log.Printf("Warning: failed to spool batched payload to queue: %v", err)
+		}
+	}
+	log.Printf("Spooled %d batched payload(s) to queue dir for later retry via --drain-queue", len(events))
+}
+
+// runDaemon listens on cfg's Unix socket and coalesces payloads that arrive within
+// batch_window_ms of each other into a single webhook POST carrying an "events" array. A
+// batch is also flushed unconditionally once its oldest event has waited max_batch_age_ms,
+// so a steady stream of events arriving faster than batch_window_ms apart can't hold
+// delivery off indefinitely. A batch that fails delivery is spooled via spoolBatch rather
+// than discarded, so --drain-queue can still redeliver it later.
+func runDaemon(cfg Config) error {
+	// BatchPayload is always delivered as plain JSON; the chat-service presets in format.go
+	// reshape a single Payload and have no defined batch equivalent, so refuse to start
+	// rather than silently ignoring the configured format.
+	if cfg.Format != "" && cfg.Format != "raw" {
+		return fmt.Errorf("--daemon only supports format \"raw\" (or unset); got %q — unset \"format\" in config.json to use the daemon", cfg.Format)
+	}
+
+	path, err := socketPath(cfg)
+	if err != nil {
+		return err
+	}
+
+	// Remove a stale socket left behind by a previous, no-longer-running daemon.
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("remove stale socket: %v", err)
+	}
+
+	listener, err := net.Listen("unix", path)
+	if err != nil {
+		return fmt.Errorf("listen on %s: %v", path, err)
+	}
+	defer listener.Close()
+	defer os.Remove(path)
+
+	sigCh := make(chan os.Signal, 1)
+	signal.Notify(sigCh, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		<-sigCh
+		log.Println("Daemon shutting down...")
+		listener.Close()
+	}()
+
+	batchWindow := time.Duration(cfg.BatchWindowMs) * time.Millisecond
+	maxBatchAge := time.Duration(cfg.MaxBatchAgeMs) * time.Millisecond
+
+	var mu sync.Mutex
+	var batch []Payload
+	var flushTimer *time.Timer  // idle timer: reset on every event, fires batchWindow after the last one
+	var maxAgeTimer *time.Timer // set once per batch, fires maxBatchAge after the first event regardless of idle resets
+
+	flush := func() {
+		mu.Lock()
+		if flushTimer != nil {
+			flushTimer.Stop()
+			flushTimer = nil
+		}
+		if maxAgeTimer != nil {
+			maxAgeTimer.Stop()
+			maxAgeTimer = nil
+		}
+		events := batch
+		batch = nil
+		mu.Unlock()
+
+		if len(events) == 0 {
+			return
+		}
+
+		data, err := json.Marshal(BatchPayload{Events: events})
+		if err != nil {
+			log.Printf("Warning: failed to marshal batch: %v", err)
+			return
+		}
+
+		statusCode, body, err := postOnce(cfg, data)
+		if err != nil {
+			log.Printf("Batch delivery failed: %v", err)
+			spoolBatch(cfg, events)
+			return
+		}
+		if statusCode < 200 || statusCode >= 300 {
+			log.Printf("Batch delivery failed: server returned status %d: %s", statusCode, string(body))
+			spoolBatch(cfg, events)
+			return
+		}
+		log.Printf("Delivered batch of %d event(s): %d - %s", len(events), statusCode, string(body))
+	}
+
+	log.Printf("Daemon listening on %s (batch window %s, max batch age %s)", path, batchWindow, maxBatchAge)
+
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			mu.Lock()
+			if flushTimer != nil {
+				flushTimer.Stop()
+			}
+			mu.Unlock()
+			flush()
+			return nil
+		}
+
+		scanner := bufio.NewScanner(conn)
+		for scanner.Scan() {
+			var payload Payload
+			if err := json.Unmarshal(scanner.Bytes(), &payload); err != nil {
+				log.Printf("Warning: failed to parse forwarded payload: %v", err)
+				continue
+			}
+
+			mu.Lock()
+			if len(batch) == 0 {
+				maxAgeTimer = time.AfterFunc(maxBatchAge, flush)
+			}
+			batch = append(batch, payload)
+			if flushTimer != nil {
+				flushTimer.Stop()
+			}
+			flushTimer = time.AfterFunc(batchWindow, flush)
+			mu.Unlock()
+		}
+		conn.Close()
+	}
+}
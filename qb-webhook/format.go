@@ -0,0 +1,143 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"text/template"
+)
+
+// buildRequestBody renders payload into the wire format selected by cfg.Format. "raw" (the
+// default) preserves the original behavior of marshaling Payload as-is; the chat presets
+// reshape it into the target service's schema so one binary can notify Discord, Slack or
+// Gotify without a wrapper script.
+func buildRequestBody(cfg Config, payload Payload) ([]byte, error) {
+	switch cfg.Format {
+	case "", "raw":
+		return json.Marshal(payload)
+	case "discord":
+		return json.Marshal(discordPayload(payload))
+	case "slack":
+		return json.Marshal(slackPayload(payload))
+	case "gotify":
+		return json.Marshal(gotifyPayload(payload))
+	case "template":
+		return renderBodyTemplate(cfg.BodyTemplate, payload)
+	default:
+		return nil, fmt.Errorf("unknown format: %q", cfg.Format)
+	}
+}
+
+type discordEmbedField struct {
+	Name   string `json:"name"`
+	Value  string `json:"value"`
+	Inline bool   `json:"inline"`
+}
+
+type discordEmbed struct {
+	Title  string              `json:"title"`
+	Fields []discordEmbedField `json:"fields"`
+}
+
+type discordMessage struct {
+	Content string         `json:"content"`
+	Embeds  []discordEmbed `json:"embeds"`
+}
+
+func discordPayload(p Payload) discordMessage {
+	return discordMessage{
+		Content: fmt.Sprintf("Torrent finished: %s", p.TorrentName),
+		Embeds: []discordEmbed{
+			{
+				Title: p.TorrentName,
+				Fields: []discordEmbedField{
+					{Name: "Size", Value: humanBytes(p.TorrentSize), Inline: true},
+					{Name: "Tracker", Value: p.Tracker, Inline: true},
+					{Name: "Category", Value: p.Category, Inline: true},
+				},
+			},
+		},
+	}
+}
+
+type slackMessage struct {
+	Text string `json:"text"`
+}
+
+func slackPayload(p Payload) slackMessage {
+	return slackMessage{
+		Text: fmt.Sprintf("*Torrent finished:* %s\n*Size:* %s  *Tracker:* %s  *Category:* %s",
+			p.TorrentName, humanBytes(p.TorrentSize), p.Tracker, p.Category),
+	}
+}
+
+type gotifyMessage struct {
+	Title    string `json:"title"`
+	Message  string `json:"message"`
+	Priority int    `json:"priority"`
+}
+
+func gotifyPayload(p Payload) gotifyMessage {
+	return gotifyMessage{
+		Title:    "Torrent finished",
+		Message:  fmt.Sprintf("%s (%s) via %s", p.TorrentName, humanBytes(p.TorrentSize), p.Tracker),
+		Priority: 5,
+	}
+}
+
+// templateFuncs are available to body_template in addition to the full Payload.
+var templateFuncs = template.FuncMap{
+	"humanBytes": humanBytes,
+	"shortHash":  shortHash,
+	"magnet":     magnet,
+}
+
+func renderBodyTemplate(body string, payload Payload) ([]byte, error) {
+	tmpl, err := template.New("body").Funcs(templateFuncs).Parse(body)
+	if err != nil {
+		return nil, fmt.Errorf("parse body_template: %v", err)
+	}
+
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, payload); err != nil {
+		return nil, fmt.Errorf("execute body_template: %v", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// humanBytes renders n as a human-readable size, e.g. 1536 -> "1.5 KiB".
+func humanBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
+
+// shortHash truncates an info hash to its first 8 characters for compact display.
+func shortHash(hash string) string {
+	if len(hash) <= 8 {
+		return hash
+	}
+	return hash[:8]
+}
+
+// magnet builds a magnet:?xt=urn:btih:... URI from an info hash, display name and tracker.
+// dn and tr are query-escaped so names containing "&"/"+" and tracker URLs carrying their
+// own query string (e.g. a private tracker's passkey) survive intact.
+func magnet(hash, name, tracker string) string {
+	u := "magnet:?xt=urn:btih:" + url.QueryEscape(hash)
+	if name != "" {
+		u += "&dn=" + url.QueryEscape(name)
+	}
+	if tracker != "" {
+		u += "&tr=" + url.QueryEscape(tracker)
+	}
+	return u
+}
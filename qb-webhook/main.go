@@ -20,6 +20,47 @@ type Config struct {
 	LogFile    string            `json:"log_file"`
 	Retries    int               `json:"retries"`
 	Timeout    int               `json:"timeout"` // seconds
+
+	// Secret enables HMAC-SHA256 request signing. When empty, requests are sent unsigned.
+	Secret string `json:"secret"`
+	// SignatureHeader is the header the signature is written to. Defaults to X-Hub-Signature-256.
+	SignatureHeader string `json:"signature_header"`
+
+	// Format selects the outgoing body schema: "raw" (default), "discord", "slack",
+	// "gotify", or "template".
+	Format string `json:"format"`
+	// BodyTemplate is a text/template string used when Format is "template". The full
+	// Payload is in scope as ".".
+	BodyTemplate string `json:"body_template"`
+
+	// EnrichMetainfo enables parsing the .torrent file at ContentPath (or a sibling
+	// "<content-path>.torrent") to add piece/file metadata to the payload.
+	EnrichMetainfo bool `json:"enrich_metainfo"`
+
+	// QueueDir is where payloads are spooled after all in-process retries are exhausted.
+	// Defaults to "queue" in the executable's directory.
+	QueueDir string `json:"queue_dir"`
+	// RetryBaseMs is the base delay for exponential backoff between retries. Default 1000.
+	RetryBaseMs int `json:"retry_base_ms"`
+	// RetryFactor multiplies the delay on each successive retry. Default 2.
+	RetryFactor float64 `json:"retry_factor"`
+	// MaxDelayMs caps the backoff delay before jitter is applied. Default 30000.
+	MaxDelayMs int `json:"max_delay_ms"`
+	// MaxAttempts caps the total number of delivery attempts recorded for a spooled payload,
+	// counting the attempts made before it was spooled; --drain-queue drops the payload once
+	// its count reaches this value. Default 10.
+	MaxAttempts int `json:"max_attempts"`
+
+	// SocketPath is the Unix socket the --daemon sidecar listens on, and that ordinary CLI
+	// invocations check for before posting directly. Defaults to "qb-webhook.sock" in the
+	// executable's directory.
+	SocketPath string `json:"socket_path"`
+	// BatchWindowMs is how long the daemon waits after receiving an event before flushing
+	// the batch it has coalesced, in case more events arrive. Default 5000.
+	BatchWindowMs int `json:"batch_window_ms"`
+	// MaxBatchAgeMs caps how long a batch can keep being extended by the sliding
+	// BatchWindowMs idle timer before it's flushed unconditionally. Default 30000.
+	MaxBatchAgeMs int `json:"max_batch_age_ms"`
 }
 
 // Payload represents the data sent to the webhook
@@ -44,6 +85,23 @@ type Payload struct {
 
 	// Additional metadata
 	Timestamp int64 `json:"timestamp"`
+
+	// Metainfo fields, populated from the .torrent file when enrich_metainfo is set.
+	PieceLength  int64         `json:"piece_length,omitempty"`
+	NumPieces    int           `json:"num_pieces,omitempty"`
+	Private      bool          `json:"private,omitempty"`
+	CreationDate int64         `json:"creation_date,omitempty"`
+	CreatedBy    string        `json:"created_by,omitempty"`
+	Comment      string        `json:"comment,omitempty"`
+	Files        []TorrentFile `json:"files,omitempty"`
+	AnnounceList [][]string    `json:"announce_list,omitempty"`
+	MagnetURI    string        `json:"magnet_uri,omitempty"`
+}
+
+// TorrentFile describes one file entry from a torrent's metainfo.
+type TorrentFile struct {
+	Path   string `json:"path"`
+	Length int64  `json:"length"`
 }
 
 var (
@@ -63,6 +121,15 @@ var (
 	
 	// Config flag
 	configFile = flag.String("config", "", "Path to configuration file")
+
+	// Signature self-test
+	verifySignature = flag.Bool("verify-signature", false, "Read a body from stdin and print the expected signature headers, then exit")
+
+	// Queue draining
+	drainQueueFlag = flag.Bool("drain-queue", false, "Re-attempt delivery of spooled payloads in queue_dir, then exit")
+
+	// Sidecar daemon
+	daemonFlag = flag.Bool("daemon", false, "Run as a long-lived sidecar that coalesces payloads and sends batched webhooks")
 )
 
 func main() {
@@ -70,7 +137,28 @@ func main() {
 
 	// Load configuration
 	cfg := loadConfig(*configFile)
-	
+
+	if *verifySignature {
+		if err := runVerifySignature(cfg); err != nil {
+			log.Fatalf("verify-signature failed: %v", err)
+		}
+		return
+	}
+
+	if *drainQueueFlag {
+		if err := drainQueue(cfg); err != nil {
+			log.Fatalf("drain-queue failed: %v", err)
+		}
+		return
+	}
+
+	if *daemonFlag {
+		if err := runDaemon(cfg); err != nil {
+			log.Fatalf("daemon failed: %v", err)
+		}
+		return
+	}
+
 	// Setup logging
 	// Default to stdout if log file cannot be opened
 	if cfg.LogFile != "" {
@@ -149,6 +237,20 @@ func main() {
 	
 	log.Printf("Processing torrent: %s (Hash: %s)", payload.TorrentName, payload.Hash)
 
+	if cfg.EnrichMetainfo {
+		if err := enrichPayloadWithMetainfo(&payload); err != nil {
+			log.Printf("Warning: metainfo enrichment skipped: %v", err)
+		}
+	}
+
+	if forwarded, err := forwardToDaemon(cfg, payload); forwarded {
+		if err != nil {
+			log.Fatalf("Failed to forward to daemon: %v", err)
+		}
+		log.Println("Forwarded to daemon successfully.")
+		return
+	}
+
 	if err := sendWebhook(cfg, payload); err != nil {
 		log.Fatalf("Failed to send webhook: %v", err)
 	}
@@ -158,8 +260,16 @@ func main() {
 
 func loadConfig(path string) Config {
 	cfg := Config{
-		Retries: 3,
-		Timeout: 10,
+		Retries:         3,
+		Timeout:         10,
+		SignatureHeader: "X-Hub-Signature-256",
+		Format:          "raw",
+		RetryBaseMs:     1000,
+		RetryFactor:     2,
+		MaxDelayMs:      30000,
+		MaxAttempts:     10,
+		BatchWindowMs:   5000,
+		MaxBatchAgeMs:   30000,
 		Headers: map[string]string{
 			"Content-Type": "application/json",
 			"User-Agent":   "qBittorrent-Webhook-Sender/1.0",
@@ -186,6 +296,19 @@ func loadConfig(path string) Config {
 					if fileCfg.LogFile != "" { cfg.LogFile = fileCfg.LogFile }
 					if fileCfg.Retries > 0 { cfg.Retries = fileCfg.Retries }
 					if fileCfg.Timeout > 0 { cfg.Timeout = fileCfg.Timeout }
+					if fileCfg.Secret != "" { cfg.Secret = fileCfg.Secret }
+					if fileCfg.SignatureHeader != "" { cfg.SignatureHeader = fileCfg.SignatureHeader }
+					if fileCfg.Format != "" { cfg.Format = fileCfg.Format }
+					if fileCfg.BodyTemplate != "" { cfg.BodyTemplate = fileCfg.BodyTemplate }
+					if fileCfg.EnrichMetainfo { cfg.EnrichMetainfo = fileCfg.EnrichMetainfo }
+					if fileCfg.QueueDir != "" { cfg.QueueDir = fileCfg.QueueDir }
+					if fileCfg.RetryBaseMs > 0 { cfg.RetryBaseMs = fileCfg.RetryBaseMs }
+					if fileCfg.RetryFactor > 0 { cfg.RetryFactor = fileCfg.RetryFactor }
+					if fileCfg.MaxDelayMs > 0 { cfg.MaxDelayMs = fileCfg.MaxDelayMs }
+					if fileCfg.MaxAttempts > 0 { cfg.MaxAttempts = fileCfg.MaxAttempts }
+					if fileCfg.SocketPath != "" { cfg.SocketPath = fileCfg.SocketPath }
+					if fileCfg.BatchWindowMs > 0 { cfg.BatchWindowMs = fileCfg.BatchWindowMs }
+					if fileCfg.MaxBatchAgeMs > 0 { cfg.MaxBatchAgeMs = fileCfg.MaxBatchAgeMs }
 					for k, v := range fileCfg.Headers {
 						cfg.Headers[k] = v
 					}
@@ -205,49 +328,85 @@ func loadConfig(path string) Config {
 }
 
 func sendWebhook(cfg Config, payload Payload) error {
-	jsonData, err := json.Marshal(payload)
+	jsonData, err := buildRequestBody(cfg, payload)
 	if err != nil {
-		return fmt.Errorf("json marshal error: %v", err)
-	}
-
-	client := &http.Client{
-		Timeout: time.Duration(cfg.Timeout) * time.Second,
+		return fmt.Errorf("build request body error: %v", err)
 	}
 
 	var lastErr error
 	for i := 0; i <= cfg.Retries; i++ {
 		if i > 0 {
-			time.Sleep(2 * time.Second)
-			log.Printf("Retry %d/%d...", i, cfg.Retries)
-		}
-
-		req, err := http.NewRequest("POST", cfg.WebhookURL, bytes.NewBuffer(jsonData))
-		if err != nil {
-			return fmt.Errorf("create request error: %v", err)
-		}
-
-		for k, v := range cfg.Headers {
-			req.Header.Set(k, v)
+			delay := backoffDelay(cfg, i)
+			log.Printf("Retry %d/%d in %s...", i, cfg.Retries, delay)
+			time.Sleep(delay)
 		}
 
-		resp, err := client.Do(req)
+		statusCode, body, err := postOnce(cfg, jsonData)
 		if err != nil {
 			lastErr = err
 			log.Printf("Request failed: %v", err)
 			continue
 		}
-		
-		body, _ := io.ReadAll(resp.Body)
-		resp.Body.Close()
 
-		if resp.StatusCode >= 200 && resp.StatusCode < 300 {
-			log.Printf("Success: %d - %s", resp.StatusCode, string(body))
+		if statusCode >= 200 && statusCode < 300 {
+			log.Printf("Success: %d - %s", statusCode, string(body))
 			return nil
 		}
 
-		lastErr = fmt.Errorf("server returned status %d: %s", resp.StatusCode, string(body))
+		lastErr = fmt.Errorf("server returned status %d: %s", statusCode, string(body))
 		log.Printf("Error: %v", lastErr)
 	}
 
+	if spoolErr := spoolPayload(cfg, payload, cfg.Retries+1); spoolErr != nil {
+		log.Printf("Warning: failed to spool payload to queue: %v", spoolErr)
+	} else {
+		log.Printf("Spooled payload to queue dir for later retry via --drain-queue")
+	}
+
 	return lastErr
 }
+
+// postOnce sends jsonData to cfg.WebhookURL exactly once, signing it if cfg.Secret is set,
+// and returns the response status code and body.
+func postOnce(cfg Config, jsonData []byte) (int, []byte, error) {
+	client := &http.Client{
+		Timeout: time.Duration(cfg.Timeout) * time.Second,
+	}
+
+	req, err := http.NewRequest("POST", cfg.WebhookURL, bytes.NewBuffer(jsonData))
+	if err != nil {
+		return 0, nil, fmt.Errorf("create request error: %v", err)
+	}
+
+	for k, v := range cfg.Headers {
+		req.Header.Set(k, v)
+	}
+
+	if cfg.Secret != "" {
+		timestamp := fmt.Sprintf("%d", time.Now().Unix())
+		nonce, err := generateNonce()
+		if err != nil {
+			return 0, nil, fmt.Errorf("generate nonce error: %v", err)
+		}
+		signature := signBody(cfg.Secret, timestamp, nonce, jsonData)
+
+		req.Header.Set("X-Webhook-Timestamp", timestamp)
+		req.Header.Set("X-Webhook-Nonce", nonce)
+		req.Header.Set(cfg.SignatureHeader, "sha256="+signature)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return 0, nil, err
+	}
+	defer resp.Body.Close()
+
+	body, _ := io.ReadAll(resp.Body)
+	return resp.StatusCode, body, nil
+}
+
+// backoffDelay computes the exponential-backoff delay (with full jitter) before retry
+// attempt n, using cfg.RetryBaseMs/RetryFactor/MaxDelayMs.
+func backoffDelay(cfg Config, attempt int) time.Duration {
+	return queuedBackoffDelay(cfg, attempt)
+}
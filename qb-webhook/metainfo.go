@@ -0,0 +1,243 @@
+package main
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// parsedTorrent holds the subset of a .torrent file's metainfo that enrichPayloadWithMetainfo
+// needs, decoded by the minimal bencode parser in bencode.go.
+type parsedTorrent struct {
+	Announce     string
+	AnnounceList [][]string
+	CreationDate int64
+	Comment      string
+	CreatedBy    string
+	Info         torrentInfo
+	InfoHash     [20]byte
+}
+
+type torrentInfo struct {
+	Name        string
+	PieceLength int64
+	NumPieces   int
+	Private     bool
+	Length      int64         // set in single-file mode
+	Files       []TorrentFile // set in multi-file mode
+}
+
+// parseTorrentFile decodes path as bencode and extracts the metainfo fields
+// enrichPayloadWithMetainfo cares about. The info hash is computed over the raw bencoded
+// bytes of the "info" dict, matching how BitTorrent clients derive it.
+func parseTorrentFile(path string) (*parsedTorrent, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read torrent file: %v", err)
+	}
+
+	d := newBDecoder(data)
+	if len(d.data) == 0 || d.data[0] != 'd' {
+		return nil, fmt.Errorf("torrent file is not a bencoded dictionary")
+	}
+	d.pos++ // consume the top-level 'd'
+
+	pt := &parsedTorrent{}
+	for {
+		if d.pos >= len(d.data) {
+			return nil, fmt.Errorf("unterminated top-level dictionary")
+		}
+		if d.data[d.pos] == 'e' {
+			d.pos++
+			break
+		}
+
+		key, err := d.decodeString()
+		if err != nil {
+			return nil, fmt.Errorf("decode top-level key: %v", err)
+		}
+
+		switch key {
+		case "announce":
+			if pt.Announce, err = d.decodeString(); err != nil {
+				return nil, err
+			}
+		case "announce-list":
+			v, err := d.decode()
+			if err != nil {
+				return nil, err
+			}
+			pt.AnnounceList = toAnnounceList(v)
+		case "creation date":
+			if pt.CreationDate, err = d.decodeInt(); err != nil {
+				return nil, err
+			}
+		case "comment":
+			if pt.Comment, err = d.decodeString(); err != nil {
+				return nil, err
+			}
+		case "created by":
+			if pt.CreatedBy, err = d.decodeString(); err != nil {
+				return nil, err
+			}
+		case "info":
+			start := d.pos
+			v, err := d.decode()
+			if err != nil {
+				return nil, fmt.Errorf("decode info dict: %v", err)
+			}
+			pt.InfoHash = sha1.Sum(data[start:d.pos])
+
+			info, ok := v.(map[string]interface{})
+			if !ok {
+				return nil, fmt.Errorf("info is not a dictionary")
+			}
+			pt.Info = parseInfoDict(info)
+		default:
+			if _, err := d.decode(); err != nil {
+				return nil, fmt.Errorf("skip key %q: %v", key, err)
+			}
+		}
+	}
+
+	return pt, nil
+}
+
+func parseInfoDict(m map[string]interface{}) torrentInfo {
+	info := torrentInfo{}
+
+	if v, ok := m["name"].(string); ok {
+		info.Name = v
+	}
+	if v, ok := m["piece length"].(int64); ok {
+		info.PieceLength = v
+	}
+	if v, ok := m["pieces"].(string); ok {
+		info.NumPieces = len(v) / sha1.Size
+	}
+	if v, ok := m["private"].(int64); ok {
+		info.Private = v == 1
+	}
+	if v, ok := m["length"].(int64); ok {
+		info.Length = v
+	}
+
+	if rawFiles, ok := m["files"].([]interface{}); ok {
+		for _, rf := range rawFiles {
+			fm, ok := rf.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			var length int64
+			if l, ok := fm["length"].(int64); ok {
+				length = l
+			}
+			var segments []string
+			if rawPath, ok := fm["path"].([]interface{}); ok {
+				for _, p := range rawPath {
+					if s, ok := p.(string); ok {
+						segments = append(segments, s)
+					}
+				}
+			}
+			info.Files = append(info.Files, TorrentFile{Path: strings.Join(segments, "/"), Length: length})
+		}
+	}
+
+	return info
+}
+
+// toAnnounceList converts the decoded announce-list (a list of lists of strings) into
+// [][]string, skipping any malformed entries rather than failing the whole parse.
+func toAnnounceList(v interface{}) [][]string {
+	tiers, ok := v.([]interface{})
+	if !ok {
+		return nil
+	}
+
+	var out [][]string
+	for _, rawTier := range tiers {
+		tier, ok := rawTier.([]interface{})
+		if !ok {
+			continue
+		}
+		var urls []string
+		for _, rawURL := range tier {
+			if s, ok := rawURL.(string); ok {
+				urls = append(urls, s)
+			}
+		}
+		out = append(out, urls)
+	}
+	return out
+}
+
+// enrichPayloadWithMetainfo loads the .torrent file referenced by payload.ContentPath (or a
+// sibling "<content-path>.torrent") and fills in the piece/file metadata fields. It returns
+// an error if no torrent file could be found or parsed; callers should treat that as
+// non-fatal and send the payload without the extra fields.
+func enrichPayloadWithMetainfo(payload *Payload) error {
+	path, err := locateTorrentFile(payload.ContentPath)
+	if err != nil {
+		return err
+	}
+
+	pt, err := parseTorrentFile(path)
+	if err != nil {
+		return fmt.Errorf("parse torrent file %q: %v", path, err)
+	}
+
+	payload.PieceLength = pt.Info.PieceLength
+	payload.NumPieces = pt.Info.NumPieces
+	payload.Private = pt.Info.Private
+	payload.CreationDate = pt.CreationDate
+	payload.CreatedBy = pt.CreatedBy
+	payload.Comment = pt.Comment
+	payload.AnnounceList = pt.AnnounceList
+
+	payload.Files = nil
+	if len(pt.Info.Files) == 0 {
+		payload.Files = append(payload.Files, TorrentFile{Path: pt.Info.Name, Length: pt.Info.Length})
+	} else {
+		payload.Files = pt.Info.Files
+	}
+
+	hash := hex.EncodeToString(pt.InfoHash[:])
+	if payload.Hash == "" {
+		payload.Hash = hash
+	}
+
+	primaryTracker := payload.Tracker
+	if len(pt.AnnounceList) > 0 && len(pt.AnnounceList[0]) > 0 {
+		primaryTracker = pt.AnnounceList[0][0]
+	} else if pt.Announce != "" {
+		primaryTracker = pt.Announce
+	}
+	payload.MagnetURI = magnet(hash, pt.Info.Name, primaryTracker)
+
+	return nil
+}
+
+// locateTorrentFile resolves contentPath to an actual .torrent file: contentPath itself if
+// it already has a .torrent extension, otherwise "<contentPath>.torrent".
+func locateTorrentFile(contentPath string) (string, error) {
+	if contentPath == "" {
+		return "", fmt.Errorf("content path is empty")
+	}
+
+	if strings.EqualFold(filepath.Ext(contentPath), ".torrent") {
+		if _, err := os.Stat(contentPath); err == nil {
+			return contentPath, nil
+		}
+	}
+
+	candidate := contentPath + ".torrent"
+	if _, err := os.Stat(candidate); err == nil {
+		return candidate, nil
+	}
+
+	return "", fmt.Errorf("no .torrent file found for %q", contentPath)
+}
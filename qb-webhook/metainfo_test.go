@@ -0,0 +1,197 @@
+package main
+
+import (
+	"crypto/sha1"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+)
+
+// encodeBencode is a tiny bencode encoder used only to build test fixtures; the decoder
+// under test lives in bencode.go.
+func encodeBencode(v interface{}) string {
+	switch val := v.(type) {
+	case string:
+		return fmt.Sprintf("%d:%s", len(val), val)
+	case int:
+		return fmt.Sprintf("i%de", val)
+	case int64:
+		return fmt.Sprintf("i%de", val)
+	case []interface{}:
+		out := "l"
+		for _, item := range val {
+			out += encodeBencode(item)
+		}
+		return out + "e"
+	case map[string]interface{}:
+		keys := make([]string, 0, len(val))
+		for k := range val {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		out := "d"
+		for _, k := range keys {
+			out += encodeBencode(k) + encodeBencode(val[k])
+		}
+		return out + "e"
+	default:
+		panic(fmt.Sprintf("encodeBencode: unsupported type %T", v))
+	}
+}
+
+// writeTestTorrent bencode-encodes a small multi-file metainfo dict to a temp .torrent file
+// and returns its path along with the expected info hash, computed the same way a real
+// BitTorrent client would: SHA-1 over the info dict's own bencoded bytes, not a re-encoding
+// of the parsed struct.
+func writeTestTorrent(t *testing.T) (path string, infoHash [20]byte) {
+	t.Helper()
+
+	info := map[string]interface{}{
+		"name":         "Album",
+		"piece length": int64(16384),
+		"pieces":       "01234567890123456789", // one fake 20-byte SHA-1 piece hash
+		"files": []interface{}{
+			map[string]interface{}{
+				"length": int64(100),
+				"path":   []interface{}{"disc1", "track1.mp3"},
+			},
+			map[string]interface{}{
+				"length": int64(200),
+				"path":   []interface{}{"disc1", "track2.mp3"},
+			},
+		},
+	}
+	infoBytes := encodeBencode(info)
+	infoHash = sha1.Sum([]byte(infoBytes))
+
+	top := map[string]interface{}{
+		"announce": "http://tracker.example/announce",
+		"announce-list": []interface{}{
+			[]interface{}{"http://tracker.example/announce"},
+			[]interface{}{"http://backup.example/announce"},
+		},
+		"comment":       "a test fixture",
+		"created by":    "qb-webhook tests",
+		"creation date": int64(1700000000),
+	}
+	topBytes := "d"
+	keys := make([]string, 0, len(top)+1)
+	for k := range top {
+		keys = append(keys, k)
+	}
+	keys = append(keys, "info")
+	sort.Strings(keys)
+	for _, k := range keys {
+		topBytes += encodeBencode(k)
+		if k == "info" {
+			topBytes += infoBytes
+		} else {
+			topBytes += encodeBencode(top[k])
+		}
+	}
+	topBytes += "e"
+
+	path = filepath.Join(t.TempDir(), "fixture.torrent")
+	if err := os.WriteFile(path, []byte(topBytes), 0644); err != nil {
+		t.Fatalf("write fixture torrent: %v", err)
+	}
+	return path, infoHash
+}
+
+func TestParseTorrentFileMultiFile(t *testing.T) {
+	path, wantHash := writeTestTorrent(t)
+
+	pt, err := parseTorrentFile(path)
+	if err != nil {
+		t.Fatalf("parseTorrentFile: %v", err)
+	}
+
+	if pt.Info.Name != "Album" {
+		t.Errorf("Info.Name = %q, want %q", pt.Info.Name, "Album")
+	}
+	if pt.Info.PieceLength != 16384 {
+		t.Errorf("Info.PieceLength = %d, want 16384", pt.Info.PieceLength)
+	}
+	if pt.Info.NumPieces != 1 {
+		t.Errorf("Info.NumPieces = %d, want 1", pt.Info.NumPieces)
+	}
+	if pt.Info.Private {
+		t.Errorf("Info.Private = true, want false")
+	}
+	if len(pt.Info.Files) != 2 {
+		t.Fatalf("len(Info.Files) = %d, want 2", len(pt.Info.Files))
+	}
+	if pt.Info.Files[0].Path != "disc1/track1.mp3" || pt.Info.Files[0].Length != 100 {
+		t.Errorf("Info.Files[0] = %+v, want {disc1/track1.mp3 100}", pt.Info.Files[0])
+	}
+	if pt.Info.Files[1].Path != "disc1/track2.mp3" || pt.Info.Files[1].Length != 200 {
+		t.Errorf("Info.Files[1] = %+v, want {disc1/track2.mp3 200}", pt.Info.Files[1])
+	}
+
+	if pt.Comment != "a test fixture" {
+		t.Errorf("Comment = %q, want %q", pt.Comment, "a test fixture")
+	}
+	if pt.CreatedBy != "qb-webhook tests" {
+		t.Errorf("CreatedBy = %q, want %q", pt.CreatedBy, "qb-webhook tests")
+	}
+	if pt.CreationDate != 1700000000 {
+		t.Errorf("CreationDate = %d, want 1700000000", pt.CreationDate)
+	}
+	if len(pt.AnnounceList) != 2 || pt.AnnounceList[0][0] != "http://tracker.example/announce" {
+		t.Errorf("AnnounceList = %v, want [[http://tracker.example/announce] [http://backup.example/announce]]", pt.AnnounceList)
+	}
+
+	if pt.InfoHash != wantHash {
+		t.Errorf("InfoHash = %x, want %x", pt.InfoHash, wantHash)
+	}
+}
+
+func TestEnrichPayloadWithMetainfoMultiFile(t *testing.T) {
+	path, wantHash := writeTestTorrent(t)
+	contentPath := path[:len(path)-len(".torrent")]
+
+	payload := &Payload{ContentPath: contentPath, Tracker: "http://fallback.example/announce"}
+	if err := enrichPayloadWithMetainfo(payload); err != nil {
+		t.Fatalf("enrichPayloadWithMetainfo: %v", err)
+	}
+
+	wantHashHex := fmt.Sprintf("%x", wantHash)
+	if payload.Hash != wantHashHex {
+		t.Errorf("payload.Hash = %q, want %q", payload.Hash, wantHashHex)
+	}
+	if payload.PieceLength != 16384 {
+		t.Errorf("payload.PieceLength = %d, want 16384", payload.PieceLength)
+	}
+	if len(payload.Files) != 2 {
+		t.Fatalf("len(payload.Files) = %d, want 2", len(payload.Files))
+	}
+
+	wantMagnet := magnet(wantHashHex, "Album", "http://tracker.example/announce")
+	if payload.MagnetURI != wantMagnet {
+		t.Errorf("payload.MagnetURI = %q, want %q", payload.MagnetURI, wantMagnet)
+	}
+}
+
+func TestToAnnounceListSkipsMalformedEntries(t *testing.T) {
+	v := []interface{}{
+		[]interface{}{"http://good.example/announce", 123},
+		"not-a-tier",
+		[]interface{}{"http://backup.example/announce"},
+	}
+
+	got := toAnnounceList(v)
+	want := [][]string{
+		{"http://good.example/announce"},
+		{"http://backup.example/announce"},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("toAnnounceList(%v) = %v, want %v", v, got, want)
+	}
+	for i := range want {
+		if len(got[i]) != len(want[i]) || got[i][0] != want[i][0] {
+			t.Errorf("tier %d = %v, want %v", i, got[i], want[i])
+		}
+	}
+}
@@ -0,0 +1,155 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"math"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// queueItem is the on-disk spool format for a payload that exhausted its in-process retries.
+// NextAttemptAt is a Unix time in milliseconds, since backoff delays are themselves
+// millisecond-scale and second-granularity timestamps would round them away.
+type queueItem struct {
+	Payload       Payload `json:"payload"`
+	Attempt       int     `json:"attempt"`
+	NextAttemptAt int64   `json:"next_attempt_at_ms"`
+}
+
+// queueDir resolves cfg.QueueDir, defaulting to "queue" next to the executable.
+func queueDir(cfg Config) (string, error) {
+	if cfg.QueueDir != "" {
+		return cfg.QueueDir, nil
+	}
+	ex, err := os.Executable()
+	if err != nil {
+		return "", fmt.Errorf("resolve executable path: %v", err)
+	}
+	return filepath.Join(filepath.Dir(ex), "queue"), nil
+}
+
+// spoolPayload writes payload to queue_dir as JSON so it can be redelivered later via
+// --drain-queue. attempt is the number of delivery attempts already made.
+func spoolPayload(cfg Config, payload Payload, attempt int) error {
+	dir, err := queueDir(cfg)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("create queue dir: %v", err)
+	}
+
+	item := queueItem{
+		Payload:       payload,
+		Attempt:       attempt,
+		NextAttemptAt: time.Now().Add(queuedBackoffDelay(cfg, attempt)).UnixMilli(),
+	}
+
+	data, err := json.MarshalIndent(item, "", "  ")
+	if err != nil {
+		return fmt.Errorf("marshal queue item: %v", err)
+	}
+
+	filename := fmt.Sprintf("%d-%s.json", time.Now().UnixNano(), shortHash(payload.Hash))
+	path := filepath.Join(dir, filename)
+	return os.WriteFile(path, data, 0644)
+}
+
+// queuedBackoffDelay mirrors backoffDelay but is keyed off the spooled attempt count, which
+// keeps growing across process invocations rather than resetting each time --drain-queue runs.
+func queuedBackoffDelay(cfg Config, attempt int) time.Duration {
+	base := float64(cfg.RetryBaseMs)
+	capped := base * math.Pow(cfg.RetryFactor, float64(attempt-1))
+	if max := float64(cfg.MaxDelayMs); capped > max {
+		capped = max
+	}
+	jittered := rand.Float64() * capped
+	return time.Duration(jittered) * time.Millisecond
+}
+
+// drainQueue walks queue_dir, re-attempting delivery of every spooled item whose
+// next-eligible-time has passed. Items are deleted on a 2xx response or once they reach
+// max_attempts; otherwise they're rewritten with an incremented attempt count and a new
+// next-eligible-time.
+func drainQueue(cfg Config) error {
+	dir, err := queueDir(cfg)
+	if err != nil {
+		return err
+	}
+
+	entries, err := os.ReadDir(dir)
+	if os.IsNotExist(err) {
+		log.Println("Queue directory does not exist; nothing to drain.")
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("read queue dir: %v", err)
+	}
+
+	now := time.Now().UnixMilli()
+	for _, entry := range entries {
+		if entry.IsDir() || filepath.Ext(entry.Name()) != ".json" {
+			continue
+		}
+		path := filepath.Join(dir, entry.Name())
+
+		data, err := os.ReadFile(path)
+		if err != nil {
+			log.Printf("Warning: failed to read queued item %s: %v", entry.Name(), err)
+			continue
+		}
+
+		var item queueItem
+		if err := json.Unmarshal(data, &item); err != nil {
+			log.Printf("Warning: failed to parse queued item %s: %v", entry.Name(), err)
+			continue
+		}
+
+		if item.NextAttemptAt > now {
+			log.Printf("Skipping %s: not yet eligible for retry", entry.Name())
+			continue
+		}
+
+		jsonData, err := buildRequestBody(cfg, item.Payload)
+		if err != nil {
+			log.Printf("Warning: failed to build request body for %s: %v", entry.Name(), err)
+			continue
+		}
+
+		statusCode, body, err := postOnce(cfg, jsonData)
+		if err == nil && statusCode >= 200 && statusCode < 300 {
+			log.Printf("Delivered queued payload %s: %d - %s", entry.Name(), statusCode, string(body))
+			os.Remove(path)
+			continue
+		}
+
+		if err != nil {
+			log.Printf("Queued payload %s failed: %v", entry.Name(), err)
+		} else {
+			log.Printf("Queued payload %s failed: server returned status %d: %s", entry.Name(), statusCode, string(body))
+		}
+
+		item.Attempt++
+		if item.Attempt >= cfg.MaxAttempts {
+			log.Printf("Dropping %s after %d attempts", entry.Name(), item.Attempt)
+			os.Remove(path)
+			continue
+		}
+
+		item.NextAttemptAt = time.Now().Add(queuedBackoffDelay(cfg, item.Attempt)).UnixMilli()
+		updated, err := json.MarshalIndent(item, "", "  ")
+		if err != nil {
+			log.Printf("Warning: failed to re-marshal queued item %s: %v", entry.Name(), err)
+			continue
+		}
+		if err := os.WriteFile(path, updated, 0644); err != nil {
+			log.Printf("Warning: failed to rewrite queued item %s: %v", entry.Name(), err)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,185 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestQueuedBackoffDelayBounds(t *testing.T) {
+	cfg := Config{
+		RetryBaseMs: 1000,
+		RetryFactor: 2,
+		MaxDelayMs:  30000,
+	}
+
+	cases := []struct {
+		attempt int
+		wantMax time.Duration
+	}{
+		{attempt: 1, wantMax: 1000 * time.Millisecond},
+		{attempt: 2, wantMax: 2000 * time.Millisecond},
+		{attempt: 3, wantMax: 4000 * time.Millisecond},
+		{attempt: 6, wantMax: 30000 * time.Millisecond}, // 1000*2^5 = 32000, capped at max_delay_ms
+		{attempt: 10, wantMax: 30000 * time.Millisecond},
+	}
+
+	for _, c := range cases {
+		for i := 0; i < 20; i++ { // jitter is random; sample a few times per case
+			d := queuedBackoffDelay(cfg, c.attempt)
+			if d < 0 || d > c.wantMax {
+				t.Fatalf("attempt %d: delay %s out of bounds [0, %s]", c.attempt, d, c.wantMax)
+			}
+		}
+	}
+}
+
+func TestQueuedBackoffDelayZeroFactorStillBounded(t *testing.T) {
+	cfg := Config{RetryBaseMs: 500, RetryFactor: 0, MaxDelayMs: 1000}
+	d := queuedBackoffDelay(cfg, 1)
+	if d < 0 || d > 1000*time.Millisecond {
+		t.Fatalf("delay %s out of bounds [0, 1s]", d)
+	}
+}
+
+// newDrainTestConfig builds a Config pointed at a temp queue dir and the given webhook URL.
+func newDrainTestConfig(t *testing.T, webhookURL string) Config {
+	t.Helper()
+	return Config{
+		WebhookURL:  webhookURL,
+		QueueDir:    t.TempDir(),
+		Format:      "raw",
+		RetryBaseMs: 10,
+		RetryFactor: 2,
+		MaxDelayMs:  100,
+		MaxAttempts: 3,
+		Timeout:     5,
+	}
+}
+
+// writeQueueItem spools item directly to cfg.QueueDir, bypassing spoolPayload so the test
+// controls NextAttemptAt precisely.
+func writeQueueItem(t *testing.T, cfg Config, name string, item queueItem) string {
+	t.Helper()
+	data, err := json.MarshalIndent(item, "", "  ")
+	if err != nil {
+		t.Fatalf("marshal queue item: %v", err)
+	}
+	path := filepath.Join(cfg.QueueDir, name)
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		t.Fatalf("write queue item: %v", err)
+	}
+	return path
+}
+
+func TestDrainQueueDeliversAndDeletesOnSuccess(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	cfg := newDrainTestConfig(t, server.URL)
+	path := writeQueueItem(t, cfg, "ok.json", queueItem{
+		Payload:       Payload{TorrentName: "ok"},
+		Attempt:       1,
+		NextAttemptAt: time.Now().Add(-time.Second).UnixMilli(),
+	})
+
+	if err := drainQueue(cfg); err != nil {
+		t.Fatalf("drainQueue: %v", err)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Fatalf("expected queued item to be deleted after a 2xx response, stat err = %v", err)
+	}
+}
+
+func TestDrainQueueRetriesOnFailureWithoutExceedingMaxAttempts(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	cfg := newDrainTestConfig(t, server.URL)
+	path := writeQueueItem(t, cfg, "retry.json", queueItem{
+		Payload:       Payload{TorrentName: "retry"},
+		Attempt:       1,
+		NextAttemptAt: time.Now().Add(-time.Second).UnixMilli(),
+	})
+
+	beforeDrain := time.Now().UnixMilli()
+	if err := drainQueue(cfg); err != nil {
+		t.Fatalf("drainQueue: %v", err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("expected queued item to survive a failed attempt below max_attempts: %v", err)
+	}
+	var item queueItem
+	if err := json.Unmarshal(data, &item); err != nil {
+		t.Fatalf("unmarshal rewritten queue item: %v", err)
+	}
+	if item.Attempt != 2 {
+		t.Fatalf("expected attempt to increment to 2, got %d", item.Attempt)
+	}
+	if item.NextAttemptAt < beforeDrain {
+		t.Fatalf("expected NextAttemptAt to be recomputed relative to the retry, got %d before drain started at %d", item.NextAttemptAt, beforeDrain)
+	}
+}
+
+func TestDrainQueueDropsAfterMaxAttempts(t *testing.T) {
+	var requests int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	cfg := newDrainTestConfig(t, server.URL)
+	path := writeQueueItem(t, cfg, "expire.json", queueItem{
+		Payload:       Payload{TorrentName: "expire"},
+		Attempt:       cfg.MaxAttempts - 1,
+		NextAttemptAt: time.Now().Add(-time.Second).UnixMilli(),
+	})
+
+	if err := drainQueue(cfg); err != nil {
+		t.Fatalf("drainQueue: %v", err)
+	}
+	if atomic.LoadInt32(&requests) != 1 {
+		t.Fatalf("expected exactly one delivery attempt, got %d", requests)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Fatalf("expected queued item to be dropped once max_attempts is reached, stat err = %v", err)
+	}
+}
+
+func TestDrainQueueSkipsItemsNotYetEligible(t *testing.T) {
+	var requests int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	cfg := newDrainTestConfig(t, server.URL)
+	path := writeQueueItem(t, cfg, "future.json", queueItem{
+		Payload:       Payload{TorrentName: "future"},
+		Attempt:       1,
+		NextAttemptAt: time.Now().Add(time.Hour).UnixMilli(),
+	})
+
+	if err := drainQueue(cfg); err != nil {
+		t.Fatalf("drainQueue: %v", err)
+	}
+	if atomic.LoadInt32(&requests) != 0 {
+		t.Fatalf("expected no delivery attempt for an item not yet eligible, got %d", requests)
+	}
+	if _, err := os.Stat(path); err != nil {
+		t.Fatalf("expected not-yet-eligible item to be left in place: %v", err)
+	}
+}